@@ -0,0 +1,180 @@
+// Copyright (c) 2015 Uber Technologies, Inc.
+//
+// Permission is hereby granted, free of charge, to any person obtaining a copy
+// of this software and associated documentation files (the "Software"), to deal
+// in the Software without restriction, including without limitation the rights
+// to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+// copies of the Software, and to permit persons to whom the Software is
+// furnished to do so, subject to the following conditions:
+//
+// The above copyright notice and this permission notice shall be included in
+// all copies or substantial portions of the Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+// IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+// FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+// AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+// LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+// OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN
+// THE SOFTWARE.
+
+package compile
+
+import (
+	"fmt"
+
+	"github.com/thriftrw/thriftrw-go/ast"
+	"github.com/thriftrw/thriftrw-go/wire"
+)
+
+// The following annotation keys are named for enums and enum items. All
+// annotations, these and any others, are compiled into
+// EnumSpec.Annotations / EnumItem.Annotations below. This package stores
+// them and does not generate code from them. Of these keys only go.name
+// on an enum item is read: goName() uses it as the item's name in the
+// duplicate-name check. go.name on an enum, go.json.name, go.json.omit
+// and go.deprecated are stored only; nothing in this package reads them.
+
+// goNameAnnotation names an enum or enum item's Go identifier. On an
+// enum item it replaces the IDL name in the duplicate-name check.
+const goNameAnnotation = "go.name"
+
+// goJSONNameAnnotation names the JSON tag of an enum item. It is stored
+// in EnumItem.Annotations and not read by this package.
+const goJSONNameAnnotation = "go.json.name"
+
+// goJSONOmitAnnotation, set to "true", marks an enum item to be left out
+// of its JSON form. It is stored in EnumItem.Annotations and not read by
+// this package.
+const goJSONOmitAnnotation = "go.json.omit"
+
+// goDeprecatedAnnotation holds the deprecation reason of an enum or enum
+// item. It is stored in the Annotations maps and not read by this
+// package.
+const goDeprecatedAnnotation = "go.deprecated"
+
+// EnumItem is a single item in an Enum.
+type EnumItem struct {
+	Name  string
+	Value int
+
+	// Annotations holds the item-level Thrift annotations
+	// (`(key = "value")`) attached to this item in the IDL. See the
+	// goNameAnnotation group above for which keys are named and which
+	// are read.
+	Annotations map[string]string
+}
+
+// goName is the exported Go identifier for this item: the item's Name,
+// unless overridden with a "go.name" annotation.
+func (i *EnumItem) goName() string {
+	if name, ok := i.Annotations[goNameAnnotation]; ok {
+		return name
+	}
+	return i.Name
+}
+
+// EnumSpec represents an enum defined in the Thrift file.
+//
+//	enum EnumName {
+//	    Item1, Item2 = 42
+//	}
+type EnumSpec struct {
+	Name  string
+	File  string
+	Items []EnumItem
+
+	// Annotations holds the enum-level Thrift annotations attached to
+	// this enum in the IDL.
+	Annotations map[string]string
+}
+
+// compileEnum compiles an enum defined in the AST.
+func compileEnum(src *ast.Enum) (*EnumSpec, error) {
+	items := make([]EnumItem, 0, len(src.Items))
+
+	// usedNames tracks the effective Go names (after "go.name"
+	// overrides) already claimed by an earlier item, so that a
+	// collision between two IDL names can still be disambiguated with
+	// an explicit "go.name" annotation.
+	usedNames := make(map[string]struct{})
+
+	nextValue := 0
+	for _, astItem := range src.Items {
+		value := nextValue
+		if astItem.Value != nil {
+			value = *astItem.Value
+		}
+		nextValue = value + 1
+
+		annotations, err := compileAnnotations(astItem.Annotations)
+		if err != nil {
+			return nil, compileError{
+				Target: fmt.Sprintf("%s.%s", src.Name, astItem.Name),
+				Line:   astItem.Line,
+				Reason: err,
+			}
+		}
+
+		item := EnumItem{
+			Name:        astItem.Name,
+			Value:       value,
+			Annotations: annotations,
+		}
+
+		name := item.goName()
+		if _, ok := usedNames[name]; ok {
+			return nil, compileError{
+				Target: fmt.Sprintf("%s.%s", src.Name, astItem.Name),
+				Line:   astItem.Line,
+				Reason: fmt.Errorf("the name %q has already been used", name),
+			}
+		}
+		usedNames[name] = struct{}{}
+
+		items = append(items, item)
+	}
+
+	annotations, err := compileAnnotations(src.Annotations)
+	if err != nil {
+		return nil, compileError{Target: src.Name, Line: src.Line, Reason: err}
+	}
+
+	return &EnumSpec{
+		Name:        src.Name,
+		Items:       items,
+		Annotations: annotations,
+	}, nil
+}
+
+// TypeCode for EnumSpec is always wire.TI32: enums are encoded on the
+// wire as plain i32 values.
+func (e *EnumSpec) TypeCode() wire.Type {
+	return wire.TI32
+}
+
+// Link resolves any references made by EnumSpec to other types.
+// EnumSpec does not reference any other types, so this is a no-op that
+// exists to satisfy the TypeSpec interface.
+func (e *EnumSpec) Link(scope Scope) (*EnumSpec, error) {
+	return e, nil
+}
+
+// compileAnnotations converts a list of AST annotations into the
+// name-to-value map stored on EnumSpec and EnumItem, rejecting a
+// duplicate annotation name on the same item.
+func compileAnnotations(anns []*ast.Annotation) (map[string]string, error) {
+	if len(anns) == 0 {
+		return nil, nil
+	}
+
+	result := make(map[string]string, len(anns))
+	for _, ann := range anns {
+		if _, ok := result[ann.Name]; ok {
+			return nil, fmt.Errorf("annotation %q was repeated", ann.Name)
+		}
+		result[ann.Name] = ann.Value
+	}
+
+	return result, nil
+}
@@ -25,6 +25,7 @@ import (
 	"testing"
 
 	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
 
 	"github.com/thriftrw/thriftrw-go/ast"
 	"github.com/thriftrw/thriftrw-go/idl"
@@ -59,10 +60,10 @@ func TestCompileEnumSuccess(t *testing.T) {
 			&EnumSpec{
 				Name: "Role",
 				Items: []EnumItem{
-					EnumItem{"Disabled", 0},
-					EnumItem{"User", 1},
-					EnumItem{"Moderator", 2},
-					EnumItem{"Admin", 3},
+					EnumItem{Name: "Disabled", Value: 0},
+					EnumItem{Name: "User", Value: 1},
+					EnumItem{Name: "Moderator", Value: 2},
+					EnumItem{Name: "Admin", Value: 3},
 				},
 			},
 		},
@@ -72,8 +73,8 @@ func TestCompileEnumSuccess(t *testing.T) {
 			&EnumSpec{
 				Name: "CommentStatus",
 				Items: []EnumItem{
-					EnumItem{"Visible", 12345},
-					EnumItem{"Hidden", 54321},
+					EnumItem{Name: "Visible", Value: 12345},
+					EnumItem{Name: "Hidden", Value: 54321},
 				},
 			},
 		},
@@ -83,11 +84,11 @@ func TestCompileEnumSuccess(t *testing.T) {
 			&EnumSpec{
 				Name: "foo",
 				Items: []EnumItem{
-					EnumItem{"A", 0},
-					EnumItem{"B", 1},
-					EnumItem{"C", 10},
-					EnumItem{"D", 11},
-					EnumItem{"E", 12},
+					EnumItem{Name: "A", Value: 0},
+					EnumItem{Name: "B", Value: 1},
+					EnumItem{Name: "C", Value: 10},
+					EnumItem{Name: "D", Value: 11},
+					EnumItem{Name: "E", Value: 12},
 				},
 			},
 		},
@@ -97,11 +98,11 @@ func TestCompileEnumSuccess(t *testing.T) {
 			&EnumSpec{
 				Name: "bar",
 				Items: []EnumItem{
-					EnumItem{"A", 0},
-					EnumItem{"B", 0},
-					EnumItem{"C", 1},
-					EnumItem{"D", 0},
-					EnumItem{"E", 1},
+					EnumItem{Name: "A", Value: 0},
+					EnumItem{Name: "B", Value: 0},
+					EnumItem{Name: "C", Value: 1},
+					EnumItem{Name: "D", Value: 0},
+					EnumItem{Name: "E", Value: 1},
 				},
 			},
 		},
@@ -123,6 +124,37 @@ func TestCompileEnumSuccess(t *testing.T) {
 	}
 }
 
+func TestCompileEnumAnnotations(t *testing.T) {
+	src := parseEnum(
+		`enum Status {
+			Active (go.json.name = "active"),
+			Inactive (go.deprecated = "use Active instead"),
+			Removed (go.json.omit = "true")
+		} (go.name = "StatusCode")`,
+	)
+
+	spec, err := compileEnum(src)
+	require.NoError(t, err)
+
+	assert.Equal(t, map[string]string{"go.name": "StatusCode"}, spec.Annotations)
+	assert.Equal(t, map[string]string{"go.json.name": "active"}, spec.Items[0].Annotations)
+	assert.Equal(t, map[string]string{"go.deprecated": "use Active instead"}, spec.Items[1].Annotations)
+	assert.Equal(t, map[string]string{"go.json.omit": "true"}, spec.Items[2].Annotations)
+}
+
+func TestCompileEnumGoNameDisambiguatesCollision(t *testing.T) {
+	// Two items both named "A" would normally fail the duplicate-name
+	// check (see TestCompileEnumFailure); a "go.name" override on the
+	// second lets it compile by giving it a distinct effective name.
+	src := parseEnum(`enum Mixed { A, A (go.name = "ADuplicate") }`)
+
+	spec, err := compileEnum(src)
+	require.NoError(t, err)
+
+	assert.Equal(t, "A", spec.Items[0].goName())
+	assert.Equal(t, "ADuplicate", spec.Items[1].goName())
+}
+
 func TestCompileEnumFailure(t *testing.T) {
 	tests := []struct {
 		src      string
@@ -0,0 +1,65 @@
+// Copyright (c) 2021 Uber Technologies, Inc.
+//
+// Permission is hereby granted, free of charge, to any person obtaining a copy
+// of this software and associated documentation files (the "Software"), to deal
+// in the Software without restriction, including without limitation the rights
+// to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+// copies of the Software, and to permit persons to whom the Software is
+// furnished to do so, subject to the following conditions:
+//
+// The above copyright notice and this permission notice shall be included in
+// all copies or substantial portions of the Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+// IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+// FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+// AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+// LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+// OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN
+// THE SOFTWARE.
+
+package compact
+
+import (
+	"io"
+
+	"go.uber.org/thriftrw/protocol/stream"
+	"go.uber.org/thriftrw/wire"
+)
+
+// Protocol implements the Thrift Compact Protocol. Use compact.Default
+// wherever binary.Default is used today to switch a client or server
+// over to the Compact Protocol wire format.
+//
+// Protocol is not registered under a name anywhere: this module has no
+// registry that selects a protocol by name, for this protocol or for
+// binary.Default. Callers use compact.Default directly.
+type Protocol struct{}
+
+// Default is the canonical instance of the Compact Protocol codec.
+var Default Protocol
+
+// Encode encodes the given Value and writes it to the given Writer.
+func (Protocol) Encode(v wire.Value, w io.Writer) error {
+	sw := NewStreamWriter(w)
+	return stream.Encode(&sw, v)
+}
+
+// Decode reads a Value of the given type from the given ReaderAt.
+func (Protocol) Decode(r io.ReaderAt, t wire.Type) (wire.Value, error) {
+	sr := NewStreamReader(io.NewSectionReader(r, 0, 1<<63-1))
+	return stream.Decode(&sr, t)
+}
+
+// EncodeEnveloped encodes the given Envelope and writes it to the given
+// Writer.
+func (Protocol) EncodeEnveloped(e wire.Envelope, w io.Writer) error {
+	sw := NewStreamWriter(w)
+	return stream.EncodeEnveloped(&sw, e)
+}
+
+// DecodeEnveloped reads an Envelope from the given ReaderAt.
+func (Protocol) DecodeEnveloped(r io.ReaderAt) (wire.Envelope, error) {
+	sr := NewStreamReader(io.NewSectionReader(r, 0, 1<<63-1))
+	return stream.DecodeEnveloped(&sr)
+}
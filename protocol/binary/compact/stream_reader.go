@@ -0,0 +1,505 @@
+// Copyright (c) 2021 Uber Technologies, Inc.
+//
+// Permission is hereby granted, free of charge, to any person obtaining a copy
+// of this software and associated documentation files (the "Software"), to deal
+// in the Software without restriction, including without limitation the rights
+// to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+// copies of the Software, and to permit persons to whom the Software is
+// furnished to do so, subject to the following conditions:
+//
+// The above copyright notice and this permission notice shall be included in
+// all copies or substantial portions of the Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+// IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+// FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+// AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+// LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+// OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN
+// THE SOFTWARE.
+
+package compact
+
+import (
+	"bytes"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"math"
+
+	"go.uber.org/thriftrw/internal/iface"
+	"go.uber.org/thriftrw/protocol/stream"
+	"go.uber.org/thriftrw/wire"
+)
+
+// bytesAllocThreshold bounds the size of binary values that are
+// allocated eagerly rather than streamed through a bytes.Buffer.
+const bytesAllocThreshold = 1048576 // 1MB
+
+// StreamReader provides an implementation of a "stream.Reader" for
+// Thrift's Compact Protocol.
+type StreamReader struct {
+	iface.Private
+
+	reader io.Reader
+	buffer [8]byte
+
+	// lastFieldID is the field ID most recently read at the current
+	// struct nesting level. Compact protocol field headers are usually
+	// encoded as a small delta off of this value.
+	lastFieldID int16
+
+	// fieldIDStack holds lastFieldID for each struct enclosing the one
+	// currently being read, so that it can be restored on
+	// ReadStructEnd.
+	fieldIDStack []int16
+
+	// boolPending and boolValue hold a boolean value that was already
+	// fully conveyed by a compact field header (BOOLEAN_TRUE /
+	// BOOLEAN_FALSE), so that the next ReadBool call can return it
+	// without consuming another byte from the wire.
+	boolPending bool
+	boolValue   bool
+}
+
+// NewStreamReader returns a new StreamReader that reads the Compact
+// Protocol off of r.
+func NewStreamReader(r io.Reader) StreamReader {
+	return StreamReader{reader: r}
+}
+
+func (sr *StreamReader) read(bs []byte) (int, error) {
+	n, err := sr.reader.Read(bs)
+	if err == io.EOF || n < len(bs) {
+		// All EOFs are unexpected when streaming.
+		err = io.ErrUnexpectedEOF
+	}
+	return n, err
+}
+
+func (sr *StreamReader) readByte() (byte, error) {
+	bs := sr.buffer[0:1]
+	_, err := sr.read(bs)
+	return bs[0], err
+}
+
+func (sr *StreamReader) discard(n int64) error {
+	_, err := io.CopyN(ioutil.Discard, sr.reader, n)
+	if err == io.EOF {
+		err = io.ErrUnexpectedEOF
+	}
+	return err
+}
+
+// readVarint reads an unsigned LEB128 varint, as used for collection
+// sizes and (after zigzag decoding) for signed integers.
+func (sr *StreamReader) readVarint() (uint64, error) {
+	var result uint64
+	var shift uint
+	for {
+		b, err := sr.readByte()
+		if err != nil {
+			return 0, err
+		}
+
+		result |= uint64(b&0x7f) << shift
+		if b&0x80 == 0 {
+			return result, nil
+		}
+
+		shift += 7
+		if shift >= 70 {
+			return 0, fmt.Errorf("varint is too long")
+		}
+	}
+}
+
+// ReadBool reads a Thrift encoded bool value.
+func (sr *StreamReader) ReadBool() (bool, error) {
+	if sr.boolPending {
+		sr.boolPending = false
+		return sr.boolValue, nil
+	}
+
+	b, err := sr.readByte()
+	if err != nil {
+		return false, err
+	}
+
+	switch b {
+	case compactBooleanTrue:
+		return true, nil
+	case compactBooleanFalse:
+		return false, nil
+	default:
+		return false, fmt.Errorf("invalid bool value: %#x", b)
+	}
+}
+
+// ReadInt8 reads a Thrift encoded int8 value.
+func (sr *StreamReader) ReadInt8() (int8, error) {
+	b, err := sr.readByte()
+	return int8(b), err
+}
+
+// ReadInt16 reads a Thrift encoded int16 value.
+func (sr *StreamReader) ReadInt16() (int16, error) {
+	v, err := sr.readVarint()
+	return int16(unzigzag64(v)), err
+}
+
+// ReadInt32 reads a Thrift encoded int32 value.
+func (sr *StreamReader) ReadInt32() (int32, error) {
+	v, err := sr.readVarint()
+	return int32(unzigzag64(v)), err
+}
+
+// ReadInt64 reads a Thrift encoded int64 value.
+func (sr *StreamReader) ReadInt64() (int64, error) {
+	v, err := sr.readVarint()
+	return unzigzag64(v), err
+}
+
+// ReadString reads a Thrift encoded string.
+func (sr *StreamReader) ReadString() (string, error) {
+	bs, err := sr.ReadBinary()
+	return string(bs), err
+}
+
+// ReadDouble reads a Thrift encoded double, returning a float64.
+func (sr *StreamReader) ReadDouble() (float64, error) {
+	bs := sr.buffer[0:8]
+	if _, err := sr.read(bs); err != nil {
+		return 0, err
+	}
+	return math.Float64frombits(bigEndian.Uint64(bs)), nil
+}
+
+// ReadBinary reads a Thrift encoded binary type, returning a byte array.
+func (sr *StreamReader) ReadBinary() ([]byte, error) {
+	length, err := sr.readVarint()
+	if err != nil {
+		return nil, err
+	}
+
+	if length == 0 {
+		return []byte{}, nil
+	}
+
+	if length > bytesAllocThreshold {
+		var buf bytes.Buffer
+		_, err := io.CopyN(&buf, sr.reader, int64(length))
+		if err == io.EOF {
+			err = io.ErrUnexpectedEOF
+		}
+		return buf.Bytes(), err
+	}
+
+	bs := make([]byte, length)
+	_, err = sr.read(bs)
+	return bs, err
+}
+
+// ReadStructBegin reads the "beginning" of a Thrift encoded struct.
+//
+// The Compact Protocol encodes field headers as deltas off of the
+// previous field ID, so entering a struct suspends the field ID of the
+// enclosing struct (if any) until the matching ReadStructEnd.
+func (sr *StreamReader) ReadStructBegin() error {
+	sr.fieldIDStack = append(sr.fieldIDStack, sr.lastFieldID)
+	sr.lastFieldID = 0
+	return nil
+}
+
+// ReadStructEnd reads the "end" of a Thrift encoded struct. The stop field
+// was already consumed by the ReadFieldBegin call that reported it, so
+// this reads nothing and only restores the enclosing struct's field ID.
+func (sr *StreamReader) ReadStructEnd() error {
+	n := len(sr.fieldIDStack)
+	sr.lastFieldID = sr.fieldIDStack[n-1]
+	sr.fieldIDStack = sr.fieldIDStack[:n-1]
+	return nil
+}
+
+// ReadFieldBegin reads off a Thrift encoded field header, reporting
+// whether a field follows or the struct's stop field was reached.
+func (sr *StreamReader) ReadFieldBegin() (stream.FieldHeader, bool, error) {
+	fh := stream.FieldHeader{}
+
+	header, err := sr.readByte()
+	if err != nil {
+		return fh, false, err
+	}
+
+	if header == compactStop {
+		return fh, false, nil
+	}
+
+	typeID := header & 0x0f
+	delta := (header >> 4) & 0x0f
+
+	if delta == 0 {
+		// Long form: the field ID didn't fit in a 4-bit delta, so it
+		// was written out in full as a zigzag varint.
+		id, err := sr.ReadInt16()
+		if err != nil {
+			return fh, false, err
+		}
+		sr.lastFieldID = id
+	} else {
+		sr.lastFieldID += int16(delta)
+	}
+	fh.ID = sr.lastFieldID
+
+	switch typeID {
+	case compactBooleanTrue:
+		sr.boolPending = true
+		sr.boolValue = true
+		fh.Type = wire.TBool
+	case compactBooleanFalse:
+		sr.boolPending = true
+		sr.boolValue = false
+		fh.Type = wire.TBool
+	default:
+		t, err := compactToWire(typeID)
+		if err != nil {
+			return fh, false, err
+		}
+		fh.Type = t
+	}
+
+	return fh, true, nil
+}
+
+// ReadFieldEnd reads the "end" of a Thrift encoded field. Since there is
+// no encoding for the end of a field, this is a noop.
+func (sr *StreamReader) ReadFieldEnd() error {
+	return nil
+}
+
+// readCollectionHeader reads the shared short/extended size-and-type
+// header used by compact lists and sets: a single byte holding a 4-bit
+// size (or 0xf to signal an extended form) and a 4-bit element type,
+// optionally followed by the size as a varint.
+func (sr *StreamReader) readCollectionHeader() (wire.Type, int, error) {
+	b, err := sr.readByte()
+	if err != nil {
+		return 0, 0, err
+	}
+
+	sizeNibble := (b >> 4) & 0x0f
+	typeNibble := b & 0x0f
+
+	size := int(sizeNibble)
+	if sizeNibble == 0x0f {
+		v, err := sr.readVarint()
+		if err != nil {
+			return 0, 0, err
+		}
+		size = int(v)
+	}
+
+	t, err := compactToWire(typeNibble)
+	if err != nil {
+		return 0, 0, err
+	}
+
+	return t, size, nil
+}
+
+// ReadListBegin reads off the list header of a Thrift encoded list.
+func (sr *StreamReader) ReadListBegin() (stream.ListHeader, error) {
+	lh := stream.ListHeader{}
+	t, size, err := sr.readCollectionHeader()
+	if err != nil {
+		return lh, err
+	}
+	lh.Type = t
+	lh.Length = size
+	return lh, nil
+}
+
+// ReadListEnd reads the "end" of a Thrift encoded list. Since there is
+// no encoding for the end of a list, this is a noop.
+func (sr *StreamReader) ReadListEnd() error {
+	return nil
+}
+
+// ReadSetBegin reads off the set header of a Thrift encoded set.
+func (sr *StreamReader) ReadSetBegin() (stream.SetHeader, error) {
+	sh := stream.SetHeader{}
+	t, size, err := sr.readCollectionHeader()
+	if err != nil {
+		return sh, err
+	}
+	sh.Type = t
+	sh.Length = size
+	return sh, nil
+}
+
+// ReadSetEnd reads the "end" of a Thrift encoded set. Since there is no
+// encoding for the end of a set, this is a noop.
+func (sr *StreamReader) ReadSetEnd() error {
+	return nil
+}
+
+// ReadMapBegin reads off the map header of a Thrift encoded map.
+func (sr *StreamReader) ReadMapBegin() (stream.MapHeader, error) {
+	mh := stream.MapHeader{}
+
+	size, err := sr.readVarint()
+	if err != nil {
+		return mh, err
+	}
+	mh.Length = int(size)
+
+	if size == 0 {
+		// An empty map has no key/value type byte.
+		return mh, nil
+	}
+
+	b, err := sr.readByte()
+	if err != nil {
+		return mh, err
+	}
+
+	keyType, err := compactToWire((b >> 4) & 0x0f)
+	if err != nil {
+		return mh, err
+	}
+	valueType, err := compactToWire(b & 0x0f)
+	if err != nil {
+		return mh, err
+	}
+
+	mh.KeyType = keyType
+	mh.ValueType = valueType
+	return mh, nil
+}
+
+// ReadMapEnd reads the "end" of a Thrift encoded map. Since there is no
+// encoding for the end of a map, this is a noop.
+func (sr *StreamReader) ReadMapEnd() error {
+	return nil
+}
+
+// Skip skips fully over the provided Thrift type.
+func (sr *StreamReader) Skip(t wire.Type) error {
+	if t == wire.TBool && sr.boolPending {
+		// The value was already consumed from the field header.
+		sr.boolPending = false
+		return nil
+	}
+
+	if w := fixedWidth(t); w > 0 {
+		return sr.discard(w)
+	}
+
+	switch t {
+	case wire.TI16, wire.TI32, wire.TI64:
+		_, err := sr.readVarint()
+		return err
+	case wire.TDouble:
+		return sr.discard(8)
+	case wire.TBinary:
+		length, err := sr.readVarint()
+		if err != nil {
+			return err
+		}
+		return sr.discard(int64(length))
+	case wire.TStruct:
+		return sr.skipStruct()
+	case wire.TMap:
+		return sr.skipMap()
+	case wire.TSet, wire.TList:
+		return sr.skipList()
+	default:
+		return fmt.Errorf("unknown ttype %v", t)
+	}
+}
+
+func (sr *StreamReader) skipStruct() error {
+	if err := sr.ReadStructBegin(); err != nil {
+		return err
+	}
+
+	for {
+		fh, more, err := sr.ReadFieldBegin()
+		if err != nil {
+			return err
+		}
+		if !more {
+			break
+		}
+
+		if err := sr.Skip(fh.Type); err != nil {
+			return err
+		}
+
+		if err := sr.ReadFieldEnd(); err != nil {
+			return err
+		}
+	}
+
+	return sr.ReadStructEnd()
+}
+
+func (sr *StreamReader) skipMap() error {
+	size, err := sr.readVarint()
+	if err != nil {
+		return err
+	}
+	if size == 0 {
+		return nil
+	}
+
+	b, err := sr.readByte()
+	if err != nil {
+		return err
+	}
+
+	keyType, err := compactToWire((b >> 4) & 0x0f)
+	if err != nil {
+		return err
+	}
+	valueType, err := compactToWire(b & 0x0f)
+	if err != nil {
+		return err
+	}
+
+	keyWidth := fixedWidth(keyType)
+	valueWidth := fixedWidth(valueType)
+	if keyWidth > 0 && valueWidth > 0 {
+		return sr.discard(int64(size) * (keyWidth + valueWidth))
+	}
+
+	for i := uint64(0); i < size; i++ {
+		if err := sr.Skip(keyType); err != nil {
+			return err
+		}
+		if err := sr.Skip(valueType); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+func (sr *StreamReader) skipList() error {
+	elemType, size, err := sr.readCollectionHeader()
+	if err != nil {
+		return err
+	}
+
+	if width := fixedWidth(elemType); width > 0 {
+		return sr.discard(width * int64(size))
+	}
+
+	for i := 0; i < size; i++ {
+		if err := sr.Skip(elemType); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
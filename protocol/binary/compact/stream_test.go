@@ -0,0 +1,263 @@
+// Copyright (c) 2021 Uber Technologies, Inc.
+//
+// Permission is hereby granted, free of charge, to any person obtaining a copy
+// of this software and associated documentation files (the "Software"), to deal
+// in the Software without restriction, including without limitation the rights
+// to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+// copies of the Software, and to permit persons to whom the Software is
+// furnished to do so, subject to the following conditions:
+//
+// The above copyright notice and this permission notice shall be included in
+// all copies or substantial portions of the Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+// IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+// FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+// AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+// LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+// OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN
+// THE SOFTWARE.
+
+package compact
+
+import (
+	"bytes"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"go.uber.org/thriftrw/protocol/stream"
+	"go.uber.org/thriftrw/wire"
+)
+
+func TestZigzagRoundTrip(t *testing.T) {
+	ints32 := []int32{0, 1, -1, 2, -2, 1 << 20, -(1 << 20), 1<<31 - 1, -(1 << 31)}
+	for _, v := range ints32 {
+		assert.Equal(t, v, unzigzag32(zigzag32(v)), "zigzag32(%v)", v)
+	}
+
+	ints64 := []int64{0, 1, -1, 1 << 40, -(1 << 40), 1<<63 - 1, -(1 << 63)}
+	for _, v := range ints64 {
+		assert.Equal(t, v, unzigzag64(zigzag64(v)), "zigzag64(%v)", v)
+	}
+}
+
+func TestStreamPrimitivesRoundTrip(t *testing.T) {
+	var buf bytes.Buffer
+	sw := NewStreamWriter(&buf)
+
+	require.NoError(t, sw.WriteBool(true))
+	require.NoError(t, sw.WriteInt8(-42))
+	require.NoError(t, sw.WriteInt16(-12345))
+	require.NoError(t, sw.WriteInt32(123456789))
+	require.NoError(t, sw.WriteInt64(-987654321012345))
+	require.NoError(t, sw.WriteDouble(3.14159))
+	require.NoError(t, sw.WriteString("hello, compact"))
+
+	sr := NewStreamReader(&buf)
+
+	b, err := sr.ReadBool()
+	require.NoError(t, err)
+	assert.True(t, b)
+
+	i8, err := sr.ReadInt8()
+	require.NoError(t, err)
+	assert.Equal(t, int8(-42), i8)
+
+	i16, err := sr.ReadInt16()
+	require.NoError(t, err)
+	assert.Equal(t, int16(-12345), i16)
+
+	i32, err := sr.ReadInt32()
+	require.NoError(t, err)
+	assert.Equal(t, int32(123456789), i32)
+
+	i64, err := sr.ReadInt64()
+	require.NoError(t, err)
+	assert.Equal(t, int64(-987654321012345), i64)
+
+	d, err := sr.ReadDouble()
+	require.NoError(t, err)
+	assert.Equal(t, 3.14159, d)
+
+	s, err := sr.ReadString()
+	require.NoError(t, err)
+	assert.Equal(t, "hello, compact", s)
+}
+
+func TestStreamFieldHeadersRoundTrip(t *testing.T) {
+	var buf bytes.Buffer
+	sw := NewStreamWriter(&buf)
+
+	require.NoError(t, sw.WriteStructBegin())
+	require.NoError(t, sw.WriteFieldBegin(stream.FieldHeader{ID: 1, Type: wire.TBool}))
+	require.NoError(t, sw.WriteBool(true))
+	require.NoError(t, sw.WriteFieldEnd())
+
+	require.NoError(t, sw.WriteFieldBegin(stream.FieldHeader{ID: 2, Type: wire.TBool}))
+	require.NoError(t, sw.WriteBool(false))
+	require.NoError(t, sw.WriteFieldEnd())
+
+	// A large jump in field ID forces the header into long form.
+	require.NoError(t, sw.WriteFieldBegin(stream.FieldHeader{ID: 100, Type: wire.TI32}))
+	require.NoError(t, sw.WriteInt32(7))
+	require.NoError(t, sw.WriteFieldEnd())
+
+	require.NoError(t, sw.WriteStructEnd())
+
+	sr := NewStreamReader(&buf)
+	require.NoError(t, sr.ReadStructBegin())
+
+	fh, more, err := sr.ReadFieldBegin()
+	require.NoError(t, err)
+	require.True(t, more)
+	assert.Equal(t, int16(1), fh.ID)
+	assert.Equal(t, wire.TBool, fh.Type)
+	v, err := sr.ReadBool()
+	require.NoError(t, err)
+	assert.True(t, v)
+	require.NoError(t, sr.ReadFieldEnd())
+
+	fh, more, err = sr.ReadFieldBegin()
+	require.NoError(t, err)
+	require.True(t, more)
+	assert.Equal(t, int16(2), fh.ID)
+	v, err = sr.ReadBool()
+	require.NoError(t, err)
+	assert.False(t, v)
+	require.NoError(t, sr.ReadFieldEnd())
+
+	fh, more, err = sr.ReadFieldBegin()
+	require.NoError(t, err)
+	require.True(t, more)
+	assert.Equal(t, int16(100), fh.ID)
+	assert.Equal(t, wire.TI32, fh.Type)
+	i, err := sr.ReadInt32()
+	require.NoError(t, err)
+	assert.Equal(t, int32(7), i)
+	require.NoError(t, sr.ReadFieldEnd())
+
+	_, more, err = sr.ReadFieldBegin()
+	require.NoError(t, err)
+	assert.False(t, more)
+
+	require.NoError(t, sr.ReadStructEnd())
+}
+
+func TestStreamBoolFieldLongFormRoundTrip(t *testing.T) {
+	var buf bytes.Buffer
+	sw := NewStreamWriter(&buf)
+
+	require.NoError(t, sw.WriteStructBegin())
+	// The jump from 0 to 20 forces long-form encoding; the field ID
+	// written on the wire must still be 20, not 20+delta.
+	require.NoError(t, sw.WriteFieldBegin(stream.FieldHeader{ID: 20, Type: wire.TBool}))
+	require.NoError(t, sw.WriteBool(true))
+	require.NoError(t, sw.WriteFieldEnd())
+	require.NoError(t, sw.WriteStructEnd())
+
+	sr := NewStreamReader(&buf)
+	require.NoError(t, sr.ReadStructBegin())
+
+	fh, more, err := sr.ReadFieldBegin()
+	require.NoError(t, err)
+	require.True(t, more)
+	assert.Equal(t, int16(20), fh.ID)
+	assert.Equal(t, wire.TBool, fh.Type)
+
+	v, err := sr.ReadBool()
+	require.NoError(t, err)
+	assert.True(t, v)
+	require.NoError(t, sr.ReadFieldEnd())
+
+	_, more, err = sr.ReadFieldBegin()
+	require.NoError(t, err)
+	assert.False(t, more)
+
+	require.NoError(t, sr.ReadStructEnd())
+}
+
+func TestStreamListRoundTrip(t *testing.T) {
+	tests := []struct {
+		name   string
+		length int
+	}{
+		{"short form", 3},
+		{"extended form", 20},
+	}
+
+	for _, tt := range tests {
+		var buf bytes.Buffer
+		sw := NewStreamWriter(&buf)
+		require.NoError(t, sw.WriteListBegin(stream.ListHeader{Type: wire.TI32, Length: tt.length}))
+		for i := 0; i < tt.length; i++ {
+			require.NoError(t, sw.WriteInt32(int32(i)))
+		}
+		require.NoError(t, sw.WriteListEnd())
+
+		sr := NewStreamReader(&buf)
+		lh, err := sr.ReadListBegin()
+		require.NoError(t, err, tt.name)
+		assert.Equal(t, wire.TI32, lh.Type, tt.name)
+		assert.Equal(t, tt.length, lh.Length, tt.name)
+
+		for i := 0; i < tt.length; i++ {
+			v, err := sr.ReadInt32()
+			require.NoError(t, err, tt.name)
+			assert.Equal(t, int32(i), v, tt.name)
+		}
+	}
+}
+
+func TestStreamSkipBoolList(t *testing.T) {
+	var buf bytes.Buffer
+	sw := NewStreamWriter(&buf)
+	require.NoError(t, sw.WriteListBegin(stream.ListHeader{Type: wire.TBool, Length: 3}))
+	require.NoError(t, sw.WriteBool(true))
+	require.NoError(t, sw.WriteBool(false))
+	require.NoError(t, sw.WriteBool(true))
+	require.NoError(t, sw.WriteListEnd())
+	// Sentinel value to prove Skip stopped exactly where it should.
+	require.NoError(t, sw.WriteInt8(42))
+
+	sr := NewStreamReader(&buf)
+
+	// Skip(TList) reads the collection header itself; it must not be
+	// preceded by a ReadListBegin that already consumed it.
+	require.NoError(t, sr.Skip(wire.TList))
+
+	sentinel, err := sr.ReadInt8()
+	require.NoError(t, err)
+	assert.Equal(t, int8(42), sentinel)
+}
+
+func TestStreamMapRoundTrip(t *testing.T) {
+	var buf bytes.Buffer
+	sw := NewStreamWriter(&buf)
+	require.NoError(t, sw.WriteMapBegin(stream.MapHeader{KeyType: wire.TI32, ValueType: wire.TBinary, Length: 2}))
+	require.NoError(t, sw.WriteInt32(1))
+	require.NoError(t, sw.WriteString("one"))
+	require.NoError(t, sw.WriteInt32(2))
+	require.NoError(t, sw.WriteString("two"))
+	require.NoError(t, sw.WriteMapEnd())
+
+	sr := NewStreamReader(&buf)
+	mh, err := sr.ReadMapBegin()
+	require.NoError(t, err)
+	assert.Equal(t, wire.TI32, mh.KeyType)
+	assert.Equal(t, wire.TBinary, mh.ValueType)
+	assert.Equal(t, 2, mh.Length)
+}
+
+func TestStreamEmptyMapRoundTrip(t *testing.T) {
+	var buf bytes.Buffer
+	sw := NewStreamWriter(&buf)
+	require.NoError(t, sw.WriteMapBegin(stream.MapHeader{Length: 0}))
+	require.NoError(t, sw.WriteMapEnd())
+
+	sr := NewStreamReader(&buf)
+	mh, err := sr.ReadMapBegin()
+	require.NoError(t, err)
+	assert.Equal(t, 0, mh.Length)
+}
@@ -0,0 +1,272 @@
+// Copyright (c) 2021 Uber Technologies, Inc.
+//
+// Permission is hereby granted, free of charge, to any person obtaining a copy
+// of this software and associated documentation files (the "Software"), to deal
+// in the Software without restriction, including without limitation the rights
+// to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+// copies of the Software, and to permit persons to whom the Software is
+// furnished to do so, subject to the following conditions:
+//
+// The above copyright notice and this permission notice shall be included in
+// all copies or substantial portions of the Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+// IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+// FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+// AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+// LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+// OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN
+// THE SOFTWARE.
+
+package compact
+
+import (
+	"io"
+	"math"
+
+	"go.uber.org/thriftrw/internal/iface"
+	"go.uber.org/thriftrw/protocol/stream"
+	"go.uber.org/thriftrw/wire"
+)
+
+// StreamWriter provides an implementation of a "stream.Writer" for
+// Thrift's Compact Protocol.
+type StreamWriter struct {
+	iface.Private
+
+	writer io.Writer
+	buffer [8]byte
+
+	lastFieldID  int16
+	fieldIDStack []int16
+
+	// boolFieldPending records that a WriteFieldBegin call for a bool
+	// field deferred writing its header byte until the value arrives
+	// via WriteBool, so that the header's type nibble can encode
+	// BOOLEAN_TRUE or BOOLEAN_FALSE directly instead of spending a
+	// separate byte on the value.
+	boolFieldPending bool
+	pendingFieldID   int16
+	pendingDelta     int16
+	pendingLongForm  bool
+}
+
+// NewStreamWriter returns a new StreamWriter that writes the Compact
+// Protocol to w.
+func NewStreamWriter(w io.Writer) StreamWriter {
+	return StreamWriter{writer: w}
+}
+
+func (sw *StreamWriter) write(bs []byte) error {
+	_, err := sw.writer.Write(bs)
+	return err
+}
+
+func (sw *StreamWriter) writeByte(b byte) error {
+	bs := sw.buffer[0:1]
+	bs[0] = b
+	return sw.write(bs)
+}
+
+func (sw *StreamWriter) writeVarint(v uint64) error {
+	bs := appendVarint(sw.buffer[:0], v)
+	return sw.write(bs)
+}
+
+// WriteBool writes a Thrift encoded bool value.
+//
+// If this value is for a struct field, the BOOLEAN_TRUE/BOOLEAN_FALSE
+// distinction was already folded into the field header by
+// WriteFieldBegin, and this only needs to flush that deferred header.
+func (sw *StreamWriter) WriteBool(v bool) error {
+	if sw.boolFieldPending {
+		sw.boolFieldPending = false
+		return sw.writeFieldHeader(boolTypeID(v), sw.pendingFieldID, sw.pendingDelta, sw.pendingLongForm)
+	}
+
+	if v {
+		return sw.writeByte(compactBooleanTrue)
+	}
+	return sw.writeByte(compactBooleanFalse)
+}
+
+func boolTypeID(v bool) byte {
+	if v {
+		return compactBooleanTrue
+	}
+	return compactBooleanFalse
+}
+
+// WriteInt8 writes a Thrift encoded int8 value.
+func (sw *StreamWriter) WriteInt8(v int8) error {
+	return sw.writeByte(byte(v))
+}
+
+// WriteInt16 writes a Thrift encoded int16 value.
+func (sw *StreamWriter) WriteInt16(v int16) error {
+	return sw.writeVarint(uint64(zigzag32(int32(v))))
+}
+
+// WriteInt32 writes a Thrift encoded int32 value.
+func (sw *StreamWriter) WriteInt32(v int32) error {
+	return sw.writeVarint(uint64(zigzag32(v)))
+}
+
+// WriteInt64 writes a Thrift encoded int64 value.
+func (sw *StreamWriter) WriteInt64(v int64) error {
+	return sw.writeVarint(zigzag64(v))
+}
+
+// WriteString writes a Thrift encoded string.
+func (sw *StreamWriter) WriteString(v string) error {
+	return sw.WriteBinary([]byte(v))
+}
+
+// WriteDouble writes a Thrift encoded double.
+func (sw *StreamWriter) WriteDouble(v float64) error {
+	bs := sw.buffer[0:8]
+	bigEndian.PutUint64(bs, math.Float64bits(v))
+	return sw.write(bs)
+}
+
+// WriteBinary writes a Thrift encoded binary value.
+func (sw *StreamWriter) WriteBinary(v []byte) error {
+	if err := sw.writeVarint(uint64(len(v))); err != nil {
+		return err
+	}
+	return sw.write(v)
+}
+
+// WriteStructBegin writes the "beginning" of a Thrift encoded struct.
+func (sw *StreamWriter) WriteStructBegin() error {
+	sw.fieldIDStack = append(sw.fieldIDStack, sw.lastFieldID)
+	sw.lastFieldID = 0
+	return nil
+}
+
+// WriteStructEnd writes the stop field of a Thrift encoded struct.
+func (sw *StreamWriter) WriteStructEnd() error {
+	n := len(sw.fieldIDStack)
+	sw.lastFieldID = sw.fieldIDStack[n-1]
+	sw.fieldIDStack = sw.fieldIDStack[:n-1]
+	return sw.writeByte(compactStop)
+}
+
+// WriteFieldBegin writes a Thrift encoded field header.
+//
+// Bool fields are special-cased: rather than writing a generic "bool"
+// type nibble followed by a value byte, the header itself is deferred
+// until the value is known (via WriteBool), so that it can encode
+// BOOLEAN_TRUE or BOOLEAN_FALSE directly and save a byte.
+func (sw *StreamWriter) WriteFieldBegin(fh stream.FieldHeader) error {
+	delta := fh.ID - sw.lastFieldID
+	longForm := delta <= 0 || delta > 15
+
+	if fh.Type == wire.TBool {
+		sw.boolFieldPending = true
+		sw.pendingFieldID = fh.ID
+		sw.pendingDelta = delta
+		sw.pendingLongForm = longForm
+		sw.lastFieldID = fh.ID
+		return nil
+	}
+
+	typeID, err := wireToCompact(fh.Type)
+	if err != nil {
+		return err
+	}
+
+	if err := sw.writeFieldHeader(typeID, fh.ID, delta, longForm); err != nil {
+		return err
+	}
+	sw.lastFieldID = fh.ID
+	return nil
+}
+
+// writeFieldHeader writes the header byte (and, in long form, the
+// zigzag-encoded field ID) for a field of the given compact type ID.
+// fieldID is the field's full ID, written out directly in long form;
+// delta is the field ID delta computed by WriteFieldBegin, used only in
+// the short form. Both are captured by the caller before lastFieldID is
+// updated, so this never depends on mutable writer state.
+func (sw *StreamWriter) writeFieldHeader(typeID byte, fieldID int16, delta int16, longForm bool) error {
+	if longForm {
+		if err := sw.writeByte(typeID); err != nil {
+			return err
+		}
+		return sw.WriteInt16(fieldID)
+	}
+
+	return sw.writeByte(byte(delta)<<4 | typeID)
+}
+
+// WriteFieldEnd writes the "end" of a Thrift encoded field. Since there
+// is no encoding for the end of a field, this is a noop.
+func (sw *StreamWriter) WriteFieldEnd() error {
+	return nil
+}
+
+func (sw *StreamWriter) writeCollectionHeader(t wire.Type, size int) error {
+	typeID, err := wireToCompact(t)
+	if err != nil {
+		return err
+	}
+
+	if size < 15 {
+		return sw.writeByte(byte(size)<<4 | typeID)
+	}
+
+	if err := sw.writeByte(0xf0 | typeID); err != nil {
+		return err
+	}
+	return sw.writeVarint(uint64(size))
+}
+
+// WriteListBegin writes the list header of a Thrift encoded list.
+func (sw *StreamWriter) WriteListBegin(lh stream.ListHeader) error {
+	return sw.writeCollectionHeader(lh.Type, lh.Length)
+}
+
+// WriteListEnd writes the "end" of a Thrift encoded list. Since there is
+// no encoding for the end of a list, this is a noop.
+func (sw *StreamWriter) WriteListEnd() error {
+	return nil
+}
+
+// WriteSetBegin writes the set header of a Thrift encoded set.
+func (sw *StreamWriter) WriteSetBegin(sh stream.SetHeader) error {
+	return sw.writeCollectionHeader(sh.Type, sh.Length)
+}
+
+// WriteSetEnd writes the "end" of a Thrift encoded set. Since there is
+// no encoding for the end of a set, this is a noop.
+func (sw *StreamWriter) WriteSetEnd() error {
+	return nil
+}
+
+// WriteMapBegin writes the map header of a Thrift encoded map.
+func (sw *StreamWriter) WriteMapBegin(mh stream.MapHeader) error {
+	if err := sw.writeVarint(uint64(mh.Length)); err != nil {
+		return err
+	}
+	if mh.Length == 0 {
+		return nil
+	}
+
+	keyType, err := wireToCompact(mh.KeyType)
+	if err != nil {
+		return err
+	}
+	valueType, err := wireToCompact(mh.ValueType)
+	if err != nil {
+		return err
+	}
+
+	return sw.writeByte(keyType<<4 | valueType)
+}
+
+// WriteMapEnd writes the "end" of a Thrift encoded map. Since there is
+// no encoding for the end of a map, this is a noop.
+func (sw *StreamWriter) WriteMapEnd() error {
+	return nil
+}
@@ -0,0 +1,127 @@
+// Copyright (c) 2021 Uber Technologies, Inc.
+//
+// Permission is hereby granted, free of charge, to any person obtaining a copy
+// of this software and associated documentation files (the "Software"), to deal
+// in the Software without restriction, including without limitation the rights
+// to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+// copies of the Software, and to permit persons to whom the Software is
+// furnished to do so, subject to the following conditions:
+//
+// The above copyright notice and this permission notice shall be included in
+// all copies or substantial portions of the Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+// IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+// FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+// AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+// LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+// OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN
+// THE SOFTWARE.
+
+package compact
+
+import (
+	"fmt"
+
+	"go.uber.org/thriftrw/wire"
+)
+
+// Compact protocol type IDs. These are distinct from the generic
+// wire.Type values: the compact protocol uses a smaller, denser set of
+// type tags so that booleans can be folded into field headers and so
+// that short-form collection headers fit in a single byte.
+const (
+	compactStop         = 0x00
+	compactBooleanTrue  = 0x01
+	compactBooleanFalse = 0x02
+	compactByte         = 0x03
+	compactI16          = 0x04
+	compactI32          = 0x05
+	compactI64          = 0x06
+	compactDouble       = 0x07
+	compactBinary       = 0x08
+	compactList         = 0x09
+	compactSet          = 0x0a
+	compactMap          = 0x0b
+	compactStruct       = 0x0c
+)
+
+// wireToCompact maps a generic wire.Type to the compact protocol type ID
+// used for collection and map element headers. Booleans are mapped to
+// compactBooleanTrue; callers that need to distinguish true/false in a
+// field header do so themselves.
+func wireToCompact(t wire.Type) (byte, error) {
+	switch t {
+	case wire.TBool:
+		return compactBooleanTrue, nil
+	case wire.TI8:
+		return compactByte, nil
+	case wire.TI16:
+		return compactI16, nil
+	case wire.TI32:
+		return compactI32, nil
+	case wire.TI64:
+		return compactI64, nil
+	case wire.TDouble:
+		return compactDouble, nil
+	case wire.TBinary:
+		return compactBinary, nil
+	case wire.TStruct:
+		return compactStruct, nil
+	case wire.TMap:
+		return compactMap, nil
+	case wire.TSet:
+		return compactSet, nil
+	case wire.TList:
+		return compactList, nil
+	default:
+		return 0, fmt.Errorf("unknown ttype %v", t)
+	}
+}
+
+// compactToWire is the inverse of wireToCompact. Both boolean type IDs
+// map to wire.TBool; the caller is responsible for recovering the actual
+// boolean value from the type ID where that matters (field headers).
+func compactToWire(t byte) (wire.Type, error) {
+	switch t {
+	case compactBooleanTrue, compactBooleanFalse:
+		return wire.TBool, nil
+	case compactByte:
+		return wire.TI8, nil
+	case compactI16:
+		return wire.TI16, nil
+	case compactI32:
+		return wire.TI32, nil
+	case compactI64:
+		return wire.TI64, nil
+	case compactDouble:
+		return wire.TDouble, nil
+	case compactBinary:
+		return wire.TBinary, nil
+	case compactStruct:
+		return wire.TStruct, nil
+	case compactMap:
+		return wire.TMap, nil
+	case compactSet:
+		return wire.TSet, nil
+	case compactList:
+		return wire.TList, nil
+	default:
+		return 0, fmt.Errorf("unknown compact type %#x", t)
+	}
+}
+
+// fixedWidth returns the fixed on-wire width, in bytes, of a compact
+// protocol value of the given wire type, or -1 if the type is variable
+// length or otherwise requires decoding to measure (varints, strings,
+// collections, structs).
+func fixedWidth(t wire.Type) int64 {
+	switch t {
+	case wire.TBool, wire.TI8:
+		return 1
+	case wire.TDouble:
+		return 8
+	default:
+		return -1
+	}
+}
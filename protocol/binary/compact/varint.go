@@ -0,0 +1,62 @@
+// Copyright (c) 2021 Uber Technologies, Inc.
+//
+// Permission is hereby granted, free of charge, to any person obtaining a copy
+// of this software and associated documentation files (the "Software"), to deal
+// in the Software without restriction, including without limitation the rights
+// to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+// copies of the Software, and to permit persons to whom the Software is
+// furnished to do so, subject to the following conditions:
+//
+// The above copyright notice and this permission notice shall be included in
+// all copies or substantial portions of the Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+// IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+// FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+// AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+// LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+// OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN
+// THE SOFTWARE.
+
+package compact
+
+import "encoding/binary"
+
+// bigEndian is used to encode/decode the big-endian double values that
+// the compact protocol shares with the binary protocol.
+var bigEndian = binary.BigEndian
+
+// Package-private helpers for the compact protocol's variable-length
+// integer encoding. The compact protocol encodes i16/i32/i64 as
+// zigzag-transformed unsigned LEB128 varints: each byte contributes 7
+// bits of magnitude in its low bits, with the high bit set on every byte
+// but the last.
+
+// zigzag32 maps a signed 32-bit integer onto the unsigned integers so
+// that values with small magnitude (positive or negative) encode as
+// small varints.
+func zigzag32(v int32) uint32 {
+	return uint32(v<<1) ^ uint32(v>>31)
+}
+
+func unzigzag32(v uint32) int32 {
+	return int32(v>>1) ^ -int32(v&1)
+}
+
+func zigzag64(v int64) uint64 {
+	return uint64(v<<1) ^ uint64(v>>63)
+}
+
+func unzigzag64(v uint64) int64 {
+	return int64(v>>1) ^ -int64(v&1)
+}
+
+// appendVarint appends the unsigned LEB128 encoding of v to bs, returning
+// the extended slice.
+func appendVarint(bs []byte, v uint64) []byte {
+	for v >= 0x80 {
+		bs = append(bs, byte(v)|0x80)
+		v >>= 7
+	}
+	return append(bs, byte(v))
+}
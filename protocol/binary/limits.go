@@ -0,0 +1,106 @@
+// Copyright (c) 2021 Uber Technologies, Inc.
+//
+// Permission is hereby granted, free of charge, to any person obtaining a copy
+// of this software and associated documentation files (the "Software"), to deal
+// in the Software without restriction, including without limitation the rights
+// to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+// copies of the Software, and to permit persons to whom the Software is
+// furnished to do so, subject to the following conditions:
+//
+// The above copyright notice and this permission notice shall be included in
+// all copies or substantial portions of the Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+// IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+// FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+// AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+// LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+// OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN
+// THE SOFTWARE.
+
+package binary
+
+import "fmt"
+
+// LimitKind identifies which StreamReaderOptions limit was exceeded.
+type LimitKind int
+
+const (
+	// LimitBinaryLength means a string or binary field declared a
+	// length over MaxBinaryLength.
+	LimitBinaryLength LimitKind = iota + 1
+
+	// LimitCollectionSize means a list, set, or map header declared a
+	// size over MaxCollectionSize.
+	LimitCollectionSize
+
+	// LimitContainerDepth means a struct, list, set, or map was nested
+	// more than MaxContainerDepth levels deep.
+	LimitContainerDepth
+
+	// LimitTotalBytes means the reader consumed more than
+	// MaxTotalBytes from the underlying io.Reader over its lifetime.
+	LimitTotalBytes
+)
+
+func (k LimitKind) String() string {
+	switch k {
+	case LimitBinaryLength:
+		return "binary length"
+	case LimitCollectionSize:
+		return "collection size"
+	case LimitContainerDepth:
+		return "container depth"
+	case LimitTotalBytes:
+		return "total bytes"
+	default:
+		return fmt.Sprintf("LimitKind(%d)", int(k))
+	}
+}
+
+// LimitExceededError is returned by StreamReader when wire-supplied
+// lengths, sizes, or nesting exceed the limits configured in
+// StreamReaderOptions. Callers can type-assert for this error to
+// distinguish a request that is likely malicious or malformed from
+// ordinary truncation or I/O failure.
+type LimitExceededError struct {
+	// Kind identifies which limit was exceeded.
+	Kind LimitKind
+
+	// Limit is the configured limit that was exceeded.
+	Limit int64
+
+	// Got is the value (or running total) that exceeded Limit.
+	Got int64
+}
+
+func (e *LimitExceededError) Error() string {
+	return fmt.Sprintf("%v limit exceeded: got %d, want <= %d", e.Kind, e.Got, e.Limit)
+}
+
+// StreamReaderOptions controls the resource limits enforced by a
+// StreamReader while decoding. Every field defaults to zero, which
+// means "unlimited," so the zero value of StreamReaderOptions
+// reproduces the historical, unlimited behavior of NewStreamReader.
+//
+// These limits exist to let a service reject a decode-bomb (a tiny
+// payload that claims an enormous string, collection, or nesting depth)
+// before it can exhaust memory or CPU, rather than trusting every
+// wire-supplied length unconditionally.
+type StreamReaderOptions struct {
+	// MaxBinaryLength caps the length, in bytes, of any single string
+	// or binary field.
+	MaxBinaryLength int64
+
+	// MaxCollectionSize caps the number of elements in any single
+	// list, set, or map.
+	MaxCollectionSize int64
+
+	// MaxContainerDepth caps how deeply structs, lists, sets, and maps
+	// may nest within each other.
+	MaxContainerDepth int
+
+	// MaxTotalBytes caps the total number of bytes the StreamReader
+	// will consume from the underlying io.Reader over its lifetime.
+	MaxTotalBytes int64
+}
@@ -38,13 +38,28 @@ type StreamReader struct {
 
 	reader io.Reader
 	buffer [8]byte
+
+	options StreamReaderOptions
+
+	totalBytesRead int64
+	containerDepth int
 }
 
-// NewStreamReader returns a new StreamReader.
+// NewStreamReader returns a new StreamReader with no resource limits,
+// matching the historical behavior of this package: wire-supplied
+// lengths, sizes, and nesting depths are trusted as-is.
 func NewStreamReader(r io.Reader) StreamReader {
 	return StreamReader{reader: r}
 }
 
+// NewStreamReaderWithOptions returns a new StreamReader that enforces
+// the given resource limits while decoding, returning a
+// *LimitExceededError if the wire data violates them. A zero-valued
+// field in opts leaves the corresponding limit unenforced.
+func NewStreamReaderWithOptions(r io.Reader, opts StreamReaderOptions) StreamReader {
+	return StreamReader{reader: r, options: opts}
+}
+
 func (sr *StreamReader) read(bs []byte) (int, error) {
 	n, err := sr.reader.Read(bs)
 
@@ -56,7 +71,11 @@ func (sr *StreamReader) read(bs []byte) (int, error) {
 		err = io.ErrUnexpectedEOF
 	}
 
-	return n, err
+	if err != nil {
+		return n, err
+	}
+
+	return n, sr.trackBytesRead(int64(n))
 }
 
 func (sr *StreamReader) discard(n int64) error {
@@ -65,8 +84,79 @@ func (sr *StreamReader) discard(n int64) error {
 		// All EOFs are unexpected when streaming
 		err = io.ErrUnexpectedEOF
 	}
+	if err != nil {
+		return err
+	}
+
+	return sr.trackBytesRead(n)
+}
+
+// trackBytesRead accounts n additional bytes consumed from the
+// underlying io.Reader against MaxTotalBytes.
+func (sr *StreamReader) trackBytesRead(n int64) error {
+	if sr.options.MaxTotalBytes <= 0 {
+		return nil
+	}
+
+	sr.totalBytesRead += n
+	if sr.totalBytesRead > sr.options.MaxTotalBytes {
+		return &LimitExceededError{
+			Kind:  LimitTotalBytes,
+			Limit: sr.options.MaxTotalBytes,
+			Got:   sr.totalBytesRead,
+		}
+	}
+
+	return nil
+}
+
+// checkBinaryLength enforces MaxBinaryLength against a wire-supplied
+// string/binary length.
+func (sr *StreamReader) checkBinaryLength(length int64) error {
+	if sr.options.MaxBinaryLength <= 0 || length <= sr.options.MaxBinaryLength {
+		return nil
+	}
+
+	return &LimitExceededError{
+		Kind:  LimitBinaryLength,
+		Limit: sr.options.MaxBinaryLength,
+		Got:   length,
+	}
+}
+
+// checkCollectionSize enforces MaxCollectionSize against a wire-supplied
+// list/set/map size.
+func (sr *StreamReader) checkCollectionSize(size int64) error {
+	if sr.options.MaxCollectionSize <= 0 || size <= sr.options.MaxCollectionSize {
+		return nil
+	}
 
-	return err
+	return &LimitExceededError{
+		Kind:  LimitCollectionSize,
+		Limit: sr.options.MaxCollectionSize,
+		Got:   size,
+	}
+}
+
+// enterContainer enforces MaxContainerDepth as the reader enters a nested
+// struct, list, set, or map, through Skip or through Read*Begin. The Skip
+// helpers pair each successful call with a deferred leaveContainer; the
+// Read*Begin methods leave it to the matching Read*End. A call that
+// returns an error leaves the depth raised.
+func (sr *StreamReader) enterContainer() error {
+	sr.containerDepth++
+	if sr.options.MaxContainerDepth > 0 && sr.containerDepth > sr.options.MaxContainerDepth {
+		return &LimitExceededError{
+			Kind:  LimitContainerDepth,
+			Limit: int64(sr.options.MaxContainerDepth),
+			Got:   int64(sr.containerDepth),
+		}
+	}
+	return nil
+}
+
+func (sr *StreamReader) leaveContainer() {
+	sr.containerDepth--
 }
 
 // ReadBool reads a Thrift encoded bool value, returning a bool.
@@ -138,6 +228,10 @@ func (sr *StreamReader) ReadBinary() ([]byte, error) {
 		return nil, fmt.Errorf("negative length %v specified for binary field", length)
 	}
 
+	if err := sr.checkBinaryLength(int64(length)); err != nil {
+		return nil, err
+	}
+
 	if length == 0 {
 		return []byte{}, nil
 	}
@@ -149,8 +243,11 @@ func (sr *StreamReader) ReadBinary() ([]byte, error) {
 			// All EOFs are unexpected when streaming
 			err = io.ErrUnexpectedEOF
 		}
+		if err != nil {
+			return buf.Bytes(), err
+		}
 
-		return buf.Bytes(), err
+		return buf.Bytes(), sr.trackBytesRead(int64(length))
 	}
 
 	bs := make([]byte, length)
@@ -158,14 +255,19 @@ func (sr *StreamReader) ReadBinary() ([]byte, error) {
 	return bs, err
 }
 
-// ReadStructBegin reads the "beginning" of a Thrift encoded struct.  Since
-// there is no encoding for the beginning of a struct, this is a noop.
+// ReadStructBegin reads the "beginning" of a Thrift encoded struct.  There
+// is no wire encoding for the beginning of a struct, so this only enforces
+// MaxContainerDepth: generated ReadWireFrom code recurses through
+// Read*Begin directly for known fields and only falls back to Skip for
+// unrecognized ones, so depth must be tracked here too, not just in Skip.
 func (sr *StreamReader) ReadStructBegin() error {
-	return nil
+	return sr.enterContainer()
 }
 
 // ReadStructEnd reads the stop field of a Thrift encoded struct.
 func (sr *StreamReader) ReadStructEnd() error {
+	defer sr.leaveContainer()
+
 	end, err := sr.ReadInt8()
 	if err != nil {
 		return err
@@ -208,6 +310,10 @@ func (sr *StreamReader) ReadFieldEnd() error {
 func (sr *StreamReader) ReadListBegin() (stream.ListHeader, error) {
 	lh := stream.ListHeader{}
 
+	if err := sr.enterContainer(); err != nil {
+		return lh, err
+	}
+
 	elemType, listSize, err := sr.readTypeSizeHeader()
 	if err != nil {
 		return lh, err
@@ -218,9 +324,11 @@ func (sr *StreamReader) ReadListBegin() (stream.ListHeader, error) {
 	return lh, nil
 }
 
-// ReadListEnd reads the "end" of a Thrift encoded list.  Since there is no
-// encoding for the end of a list, this is a noop.
+// ReadListEnd reads the "end" of a Thrift encoded list. There is no wire
+// encoding for the end of a list; this only balances the container-depth
+// tracking started by ReadListBegin.
 func (sr *StreamReader) ReadListEnd() error {
+	sr.leaveContainer()
 	return nil
 }
 
@@ -228,6 +336,10 @@ func (sr *StreamReader) ReadListEnd() error {
 func (sr *StreamReader) ReadSetBegin() (stream.SetHeader, error) {
 	sh := stream.SetHeader{}
 
+	if err := sr.enterContainer(); err != nil {
+		return sh, err
+	}
+
 	elemType, setSize, err := sr.readTypeSizeHeader()
 	if err != nil {
 		return sh, err
@@ -238,9 +350,11 @@ func (sr *StreamReader) ReadSetBegin() (stream.SetHeader, error) {
 	return sh, nil
 }
 
-// ReadSetEnd reads the "end" of a Thrift encoded list.  Since there is no
-// encoding for the end of a set, this is a noop.
+// ReadSetEnd reads the "end" of a Thrift encoded set. There is no wire
+// encoding for the end of a set; this only balances the container-depth
+// tracking started by ReadSetBegin.
 func (sr *StreamReader) ReadSetEnd() error {
+	sr.leaveContainer()
 	return nil
 }
 
@@ -259,6 +373,10 @@ func (sr *StreamReader) readTypeSizeHeader() (wire.Type, int, error) {
 		return wire.Type(0), 0, fmt.Errorf("got negative length: %v", size)
 	}
 
+	if err := sr.checkCollectionSize(int64(size)); err != nil {
+		return wire.Type(0), 0, err
+	}
+
 	return wire.Type(elemType), int(size), nil
 }
 
@@ -266,6 +384,10 @@ func (sr *StreamReader) readTypeSizeHeader() (wire.Type, int, error) {
 func (sr *StreamReader) ReadMapBegin() (stream.MapHeader, error) {
 	mh := stream.MapHeader{}
 
+	if err := sr.enterContainer(); err != nil {
+		return mh, err
+	}
+
 	keyType, err := sr.ReadInt8()
 	if err != nil {
 		return mh, err
@@ -285,15 +407,21 @@ func (sr *StreamReader) ReadMapBegin() (stream.MapHeader, error) {
 		return mh, fmt.Errorf("got negative length: %v", size)
 	}
 
+	if err := sr.checkCollectionSize(int64(size)); err != nil {
+		return mh, err
+	}
+
 	mh.KeyType = wire.Type(keyType)
 	mh.ValueType = wire.Type(valueType)
 	mh.Length = int(size)
 	return mh, nil
 }
 
-// ReadMapEnd reads the "end" of a Thrift encoded list.  Since there is no
-// encoding for the end of a map, this is a noop.
+// ReadMapEnd reads the "end" of a Thrift encoded map. There is no wire
+// encoding for the end of a map; this only balances the container-depth
+// tracking started by ReadMapBegin.
 func (sr *StreamReader) ReadMapEnd() error {
+	sr.leaveContainer()
 	return nil
 }
 
@@ -314,6 +442,10 @@ func (sr *StreamReader) Skip(t wire.Type) error {
 			return fmt.Errorf("got negative length: %v", length)
 		}
 
+		if err := sr.checkBinaryLength(int64(length)); err != nil {
+			return err
+		}
+
 		return sr.discard(int64(length))
 	case wire.TStruct:
 		return sr.skipStruct()
@@ -329,6 +461,11 @@ func (sr *StreamReader) Skip(t wire.Type) error {
 }
 
 func (sr *StreamReader) skipStruct() error {
+	if err := sr.enterContainer(); err != nil {
+		return err
+	}
+	defer sr.leaveContainer()
+
 	fieldType, err := sr.ReadInt8()
 	if err != nil {
 		return err
@@ -353,6 +490,11 @@ func (sr *StreamReader) skipStruct() error {
 }
 
 func (sr *StreamReader) skipMap() error {
+	if err := sr.enterContainer(); err != nil {
+		return err
+	}
+	defer sr.leaveContainer()
+
 	keyRaw, err := sr.ReadInt8()
 	if err != nil {
 		return err
@@ -372,6 +514,10 @@ func (sr *StreamReader) skipMap() error {
 		return fmt.Errorf("got negative length: %v", size)
 	}
 
+	if err := sr.checkCollectionSize(int64(size)); err != nil {
+		return err
+	}
+
 	key := wire.Type(keyRaw)
 	keyWidth := fixedWidth(key)
 	value := wire.Type(valueRaw)
@@ -396,6 +542,11 @@ func (sr *StreamReader) skipMap() error {
 }
 
 func (sr *StreamReader) skipList() error {
+	if err := sr.enterContainer(); err != nil {
+		return err
+	}
+	defer sr.leaveContainer()
+
 	elemType, size, err := sr.readTypeSizeHeader()
 	if err != nil {
 		return err
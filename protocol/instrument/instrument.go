@@ -0,0 +1,191 @@
+// Copyright (c) 2021 Uber Technologies, Inc.
+//
+// Permission is hereby granted, free of charge, to any person obtaining a copy
+// of this software and associated documentation files (the "Software"), to deal
+// in the Software without restriction, including without limitation the rights
+// to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+// copies of the Software, and to permit persons to whom the Software is
+// furnished to do so, subject to the following conditions:
+//
+// The above copyright notice and this permission notice shall be included in
+// all copies or substantial portions of the Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+// IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+// FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+// AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+// LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+// OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN
+// THE SOFTWARE.
+
+// Package instrument provides a protocol.Protocol wrapper that records
+// metrics for Encode, Decode, EncodeEnveloped, and DecodeEnveloped calls
+// without tying thriftrw to any particular metrics backend.
+package instrument
+
+import (
+	"io"
+	"time"
+
+	"go.uber.org/thriftrw/protocol"
+	"go.uber.org/thriftrw/wire"
+)
+
+// Counter is a monotonically increasing value, scoped to a fixed set of
+// label values.
+type Counter interface {
+	// With returns a Counter scoped to the given label values. The
+	// number and order of labelValues must match the label names the
+	// Counter was created with.
+	With(labelValues ...string) Counter
+
+	// Inc increments the counter by one.
+	Inc()
+}
+
+// Histogram records a distribution of observed values, scoped to a
+// fixed set of label values.
+type Histogram interface {
+	// With returns a Histogram scoped to the given label values. The
+	// number and order of labelValues must match the label names the
+	// Histogram was created with.
+	With(labelValues ...string) Histogram
+
+	// Observe records a single value.
+	Observe(value float64)
+}
+
+// Backend constructs the Counters and Histograms that Protocol records
+// to. Implementations adapt a specific metrics library (Prometheus,
+// Tally, OpenTelemetry, ...) to this interface; see the
+// prometheusadapter subpackage for a ready-made Prometheus Backend.
+type Backend interface {
+	// NewCounter creates a Counter named name, labeled by labelNames.
+	NewCounter(name, help string, labelNames ...string) Counter
+
+	// NewHistogram creates a Histogram named name, labeled by
+	// labelNames.
+	NewHistogram(name, help string, labelNames ...string) Histogram
+}
+
+// method identifies which Protocol method a recorded metric came from.
+type method string
+
+const (
+	methodEncode          method = "encode"
+	methodDecode          method = "decode"
+	methodEncodeEnveloped method = "encode_enveloped"
+	methodDecodeEnveloped method = "decode_enveloped"
+)
+
+// Protocol wraps a protocol.Protocol, recording per-method metrics to a
+// Backend: bytes in/out, latency, and error counts, labeled by the
+// method name. Latency and error counts also carry an envelope label:
+// for the enveloped calls, the enveloped method name from
+// wire.Envelope.Name; for Encode and Decode, the empty string.
+type Protocol struct {
+	protocol.Protocol
+
+	bytesIn  Histogram
+	bytesOut Histogram
+	latency  Histogram
+	errors   Counter
+}
+
+// New wraps p so that calls to it are recorded to backend.
+func New(p protocol.Protocol, backend Backend) *Protocol {
+	return &Protocol{
+		Protocol: p,
+		bytesIn:  backend.NewHistogram("thriftrw_protocol_bytes_in", "Bytes read per call.", "method"),
+		bytesOut: backend.NewHistogram("thriftrw_protocol_bytes_out", "Bytes written per call.", "method"),
+		latency:  backend.NewHistogram("thriftrw_protocol_latency_seconds", "Call latency in seconds.", "method", "envelope"),
+		errors:   backend.NewCounter("thriftrw_protocol_errors_total", "Number of calls that returned an error.", "method", "envelope"),
+	}
+}
+
+func (p *Protocol) observe(m method, envelopeName string, start time.Time, err error) {
+	p.latency.With(string(m), envelopeName).Observe(time.Since(start).Seconds())
+	if err != nil {
+		p.errors.With(string(m), envelopeName).Inc()
+	}
+}
+
+// Encode encodes the given Value and writes it to the given Writer,
+// recording bytes written and latency for the call.
+func (p *Protocol) Encode(v wire.Value, w io.Writer) error {
+	start := time.Now()
+	cw := &countingWriter{w: w}
+	err := p.Protocol.Encode(v, cw)
+	p.bytesOut.With(string(methodEncode)).Observe(float64(cw.n))
+	p.observe(methodEncode, "", start, err)
+	return err
+}
+
+// Decode reads a Value of the given type from the given ReaderAt,
+// recording bytes read and latency for the call.
+func (p *Protocol) Decode(r io.ReaderAt, t wire.Type) (wire.Value, error) {
+	start := time.Now()
+	cr := &countingReaderAt{r: r}
+	v, err := p.Protocol.Decode(cr, t)
+	p.bytesIn.With(string(methodDecode)).Observe(float64(cr.maxOffset()))
+	p.observe(methodDecode, "", start, err)
+	return v, err
+}
+
+// EncodeEnveloped encodes the given Envelope and writes it to the given
+// Writer, recording bytes written, and latency and errors labeled by
+// e.Name.
+func (p *Protocol) EncodeEnveloped(e wire.Envelope, w io.Writer) error {
+	start := time.Now()
+	cw := &countingWriter{w: w}
+	err := p.Protocol.EncodeEnveloped(e, cw)
+	p.bytesOut.With(string(methodEncodeEnveloped)).Observe(float64(cw.n))
+	p.observe(methodEncodeEnveloped, e.Name, start, err)
+	return err
+}
+
+// DecodeEnveloped reads an Envelope from the given ReaderAt, recording
+// bytes read, and latency and errors labeled by the decoded Envelope's
+// Name.
+func (p *Protocol) DecodeEnveloped(r io.ReaderAt) (wire.Envelope, error) {
+	start := time.Now()
+	cr := &countingReaderAt{r: r}
+	e, err := p.Protocol.DecodeEnveloped(cr)
+	p.bytesIn.With(string(methodDecodeEnveloped)).Observe(float64(cr.maxOffset()))
+	p.observe(methodDecodeEnveloped, e.Name, start, err)
+	return e, err
+}
+
+// countingWriter tracks the number of bytes written through it so that
+// Encode/EncodeEnveloped can record bytesOut without requiring the
+// wrapped protocol.Protocol to report a size up front.
+type countingWriter struct {
+	w io.Writer
+	n int64
+}
+
+func (cw *countingWriter) Write(bs []byte) (int, error) {
+	n, err := cw.w.Write(bs)
+	cw.n += int64(n)
+	return n, err
+}
+
+// countingReaderAt tracks the furthest offset read from it, which is a
+// reasonable proxy for the number of bytes consumed by a Decode call
+// since wrapped protocols never read back past the end of a value.
+type countingReaderAt struct {
+	r   io.ReaderAt
+	off int64
+}
+
+func (cr *countingReaderAt) ReadAt(bs []byte, off int64) (int, error) {
+	n, err := cr.r.ReadAt(bs, off)
+	if end := off + int64(n); end > cr.off {
+		cr.off = end
+	}
+	return n, err
+}
+
+func (cr *countingReaderAt) maxOffset() int64 {
+	return cr.off
+}
@@ -0,0 +1,145 @@
+// Copyright (c) 2021 Uber Technologies, Inc.
+//
+// Permission is hereby granted, free of charge, to any person obtaining a copy
+// of this software and associated documentation files (the "Software"), to deal
+// in the Software without restriction, including without limitation the rights
+// to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+// copies of the Software, and to permit persons to whom the Software is
+// furnished to do so, subject to the following conditions:
+//
+// The above copyright notice and this permission notice shall be included in
+// all copies or substantial portions of the Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+// IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+// FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+// AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+// LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+// OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN
+// THE SOFTWARE.
+
+package instrument
+
+import (
+	"bytes"
+	"errors"
+	"io"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"go.uber.org/thriftrw/wire"
+)
+
+// fakeProtocol is a minimal protocol.Protocol stub, letting these tests
+// control exactly what bytes and errors flow through Protocol without
+// depending on a concrete codec.
+type fakeProtocol struct {
+	encodeBytes []byte
+	encodeErr   error
+
+	decodeEnvelopedResult wire.Envelope
+	decodeEnvelopedErr    error
+}
+
+func (f *fakeProtocol) Encode(v wire.Value, w io.Writer) error {
+	if f.encodeErr != nil {
+		return f.encodeErr
+	}
+	_, err := w.Write(f.encodeBytes)
+	return err
+}
+
+func (f *fakeProtocol) Decode(r io.ReaderAt, t wire.Type) (wire.Value, error) {
+	return wire.Value{}, nil
+}
+
+func (f *fakeProtocol) EncodeEnveloped(e wire.Envelope, w io.Writer) error {
+	return f.Encode(wire.Value{}, w)
+}
+
+func (f *fakeProtocol) DecodeEnveloped(r io.ReaderAt) (wire.Envelope, error) {
+	return f.decodeEnvelopedResult, f.decodeEnvelopedErr
+}
+
+// fakeBackend is a minimal in-memory instrument.Backend used to assert
+// that Protocol records the metrics it promises without pulling in a
+// real metrics library into this package's tests.
+type fakeBackend struct {
+	counters   map[string]*fakeCounter
+	histograms map[string]*fakeHistogram
+}
+
+func newFakeBackend() *fakeBackend {
+	return &fakeBackend{
+		counters:   make(map[string]*fakeCounter),
+		histograms: make(map[string]*fakeHistogram),
+	}
+}
+
+func (b *fakeBackend) NewCounter(name, help string, labelNames ...string) Counter {
+	c := &fakeCounter{}
+	b.counters[name] = c
+	return c
+}
+
+func (b *fakeBackend) NewHistogram(name, help string, labelNames ...string) Histogram {
+	h := &fakeHistogram{}
+	b.histograms[name] = h
+	return h
+}
+
+type fakeCounter struct{ count int }
+
+func (c *fakeCounter) With(labelValues ...string) Counter { return c }
+func (c *fakeCounter) Inc()                               { c.count++ }
+
+type fakeHistogram struct {
+	observations []float64
+	labels       [][]string
+}
+
+func (h *fakeHistogram) With(labelValues ...string) Histogram {
+	h.labels = append(h.labels, labelValues)
+	return h
+}
+
+func (h *fakeHistogram) Observe(value float64) { h.observations = append(h.observations, value) }
+
+func TestProtocolEncodeRecordsBytesAndLatency(t *testing.T) {
+	inner := &fakeProtocol{encodeBytes: []byte("hello")}
+	backend := newFakeBackend()
+	p := New(inner, backend)
+
+	var buf bytes.Buffer
+	require.NoError(t, p.Encode(wire.Value{}, &buf))
+
+	assert.Equal(t, []float64{5}, backend.histograms["thriftrw_protocol_bytes_out"].observations)
+	assert.Len(t, backend.histograms["thriftrw_protocol_latency_seconds"].observations, 1)
+	assert.Equal(t, 0, backend.counters["thriftrw_protocol_errors_total"].count)
+}
+
+func TestProtocolEncodeRecordsErrors(t *testing.T) {
+	inner := &fakeProtocol{encodeErr: errors.New("boom")}
+	backend := newFakeBackend()
+	p := New(inner, backend)
+
+	var buf bytes.Buffer
+	err := p.Encode(wire.Value{}, &buf)
+	require.Error(t, err)
+
+	assert.Equal(t, 1, backend.counters["thriftrw_protocol_errors_total"].count)
+}
+
+func TestProtocolDecodeEnvelopedLabelsByEnvelopeName(t *testing.T) {
+	inner := &fakeProtocol{decodeEnvelopedResult: wire.Envelope{Name: "getValue"}}
+	backend := newFakeBackend()
+	p := New(inner, backend)
+
+	e, err := p.DecodeEnveloped(bytes.NewReader(nil))
+	require.NoError(t, err)
+	assert.Equal(t, "getValue", e.Name)
+	assert.Len(t, backend.histograms["thriftrw_protocol_latency_seconds"].observations, 1)
+	assert.Equal(t, [][]string{{"decode_enveloped", "getValue"}}, backend.histograms["thriftrw_protocol_latency_seconds"].labels)
+}
@@ -0,0 +1,128 @@
+// Copyright (c) 2021 Uber Technologies, Inc.
+//
+// Permission is hereby granted, free of charge, to any person obtaining a copy
+// of this software and associated documentation files (the "Software"), to deal
+// in the Software without restriction, including without limitation the rights
+// to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+// copies of the Software, and to permit persons to whom the Software is
+// furnished to do so, subject to the following conditions:
+//
+// The above copyright notice and this permission notice shall be included in
+// all copies or substantial portions of the Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+// IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+// FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+// AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+// LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+// OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN
+// THE SOFTWARE.
+
+// Package prometheusadapter adapts a prometheus.Registerer into the
+// instrument.Backend interface, so that protocol/instrument can be
+// plugged into a service that already exports a /metrics endpoint.
+package prometheusadapter
+
+import (
+	"github.com/prometheus/client_golang/prometheus"
+
+	"go.uber.org/thriftrw/protocol/instrument"
+)
+
+// defaultBuckets matches the histogram buckets Prometheus itself
+// recommends for sub-second latencies; thriftrw encode/decode calls are
+// expected to fall well within this range.
+var defaultBuckets = prometheus.DefBuckets
+
+// Backend adapts a prometheus.Registerer into instrument.Backend,
+// registering a CounterVec or HistogramVec per metric name on first use.
+type Backend struct {
+	registerer prometheus.Registerer
+}
+
+// New returns a Backend that registers its metrics with registerer.
+func New(registerer prometheus.Registerer) *Backend {
+	return &Backend{registerer: registerer}
+}
+
+// NewCounter creates a Counter backed by a Prometheus CounterVec.
+func (b *Backend) NewCounter(name, help string, labelNames ...string) instrument.Counter {
+	vec := prometheus.NewCounterVec(prometheus.CounterOpts{
+		Name: name,
+		Help: help,
+	}, labelNames)
+	b.registerer.MustRegister(vec)
+	return vecCounter{vec: vec, labelNames: labelNames}
+}
+
+// NewHistogram creates a Histogram backed by a Prometheus HistogramVec
+// using the standard Prometheus latency buckets.
+func (b *Backend) NewHistogram(name, help string, labelNames ...string) instrument.Histogram {
+	vec := prometheus.NewHistogramVec(prometheus.HistogramOpts{
+		Name:    name,
+		Help:    help,
+		Buckets: defaultBuckets,
+	}, labelNames)
+	b.registerer.MustRegister(vec)
+	return vecHistogram{vec: vec, labelNames: labelNames}
+}
+
+// vecCounter is the unbound instrument.Counter returned by NewCounter.
+// With binds it to a specific set of label values.
+type vecCounter struct {
+	vec        *prometheus.CounterVec
+	labelNames []string
+}
+
+func (c vecCounter) With(labelValues ...string) instrument.Counter {
+	return boundCounter{c.vec.WithLabelValues(labelValues...)}
+}
+
+// Inc satisfies instrument.Counter, which permits calling Inc without
+// calling With first. Since this vec always has labelNames (this
+// package's callers only ever request labeled counters), calling
+// WithLabelValues with no arguments would panic on a label-count
+// mismatch; bind to an all-empty-string label set of the right arity
+// instead. Callers that care about label values should call With.
+func (c vecCounter) Inc() {
+	c.With(make([]string, len(c.labelNames))...).Inc()
+}
+
+// boundCounter is an instrument.Counter already bound to a specific set
+// of label values.
+type boundCounter struct {
+	prometheus.Counter
+}
+
+func (c boundCounter) With(labelValues ...string) instrument.Counter {
+	return c
+}
+
+// vecHistogram is the unbound instrument.Histogram returned by
+// NewHistogram. With binds it to a specific set of label values.
+type vecHistogram struct {
+	vec        *prometheus.HistogramVec
+	labelNames []string
+}
+
+func (h vecHistogram) With(labelValues ...string) instrument.Histogram {
+	return boundHistogram{h.vec.WithLabelValues(labelValues...)}
+}
+
+// Observe satisfies instrument.Histogram, which permits calling Observe
+// without calling With first. See vecCounter.Inc for why this binds to
+// an all-empty-string label set instead of calling WithLabelValues with
+// no arguments.
+func (h vecHistogram) Observe(value float64) {
+	h.With(make([]string, len(h.labelNames))...).Observe(value)
+}
+
+// boundHistogram is an instrument.Histogram already bound to a specific
+// set of label values.
+type boundHistogram struct {
+	prometheus.Observer
+}
+
+func (h boundHistogram) With(labelValues ...string) instrument.Histogram {
+	return h
+}
@@ -0,0 +1,109 @@
+// Copyright (c) 2021 Uber Technologies, Inc.
+//
+// Permission is hereby granted, free of charge, to any person obtaining a copy
+// of this software and associated documentation files (the "Software"), to deal
+// in the Software without restriction, including without limitation the rights
+// to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+// copies of the Software, and to permit persons to whom the Software is
+// furnished to do so, subject to the following conditions:
+//
+// The above copyright notice and this permission notice shall be included in
+// all copies or substantial portions of the Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+// IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+// FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+// AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+// LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+// OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN
+// THE SOFTWARE.
+
+package prometheusadapter
+
+import (
+	"testing"
+
+	"github.com/prometheus/client_golang/prometheus"
+	dto "github.com/prometheus/client_model/go"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestCounterIncWithoutWithDoesNotPanic(t *testing.T) {
+	registerer := prometheus.NewRegistry()
+	backend := New(registerer)
+
+	counter := backend.NewCounter("test_counter", "help", "method", "envelope")
+
+	assert.NotPanics(t, func() { counter.Inc() })
+
+	metric := gatherMetric(t, registerer, "test_counter")
+	assert.Equal(t, []string{"", ""}, labelValues(metric))
+	assert.Equal(t, float64(1), metric.GetCounter().GetValue())
+}
+
+func TestCounterWithBindsLabelValues(t *testing.T) {
+	registerer := prometheus.NewRegistry()
+	backend := New(registerer)
+
+	counter := backend.NewCounter("test_counter_bound", "help", "method")
+	counter.With("encode").Inc()
+	counter.With("encode").Inc()
+
+	metric := gatherMetric(t, registerer, "test_counter_bound")
+	assert.Equal(t, []string{"encode"}, labelValues(metric))
+	assert.Equal(t, float64(2), metric.GetCounter().GetValue())
+}
+
+func TestHistogramObserveWithoutWithDoesNotPanic(t *testing.T) {
+	registerer := prometheus.NewRegistry()
+	backend := New(registerer)
+
+	histogram := backend.NewHistogram("test_histogram", "help", "method", "envelope")
+
+	assert.NotPanics(t, func() { histogram.Observe(1.5) })
+
+	metric := gatherMetric(t, registerer, "test_histogram")
+	assert.Equal(t, []string{"", ""}, labelValues(metric))
+	assert.Equal(t, uint64(1), metric.GetHistogram().GetSampleCount())
+}
+
+func TestHistogramWithBindsLabelValues(t *testing.T) {
+	registerer := prometheus.NewRegistry()
+	backend := New(registerer)
+
+	histogram := backend.NewHistogram("test_histogram_bound", "help", "method")
+	histogram.With("decode").Observe(0.5)
+
+	metric := gatherMetric(t, registerer, "test_histogram_bound")
+	assert.Equal(t, []string{"decode"}, labelValues(metric))
+	assert.Equal(t, uint64(1), metric.GetHistogram().GetSampleCount())
+}
+
+// gatherMetric collects the single metric family named name from
+// registerer and returns its only metric.
+func gatherMetric(t *testing.T, registerer *prometheus.Registry, name string) *dto.Metric {
+	t.Helper()
+
+	families, err := registerer.Gather()
+	require.NoError(t, err)
+
+	for _, family := range families {
+		if family.GetName() != name {
+			continue
+		}
+		require.Len(t, family.GetMetric(), 1)
+		return family.GetMetric()[0]
+	}
+
+	t.Fatalf("no metric family named %q was registered", name)
+	return nil
+}
+
+func labelValues(metric *dto.Metric) []string {
+	values := make([]string, len(metric.GetLabel()))
+	for i, label := range metric.GetLabel() {
+		values[i] = label.GetValue()
+	}
+	return values
+}